@@ -4,23 +4,29 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+
+	"github.com/Kolya0001/bot-Tg/metrics"
 )
 
 var (
 	db            *sql.DB
 	bot           *tgbotapi.BotAPI
-	logger        = log.New(os.Stdout, "BOT: ", log.LstdFlags|log.Lshortfile)
+	logger        = slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	progressCache sync.Map
+	taskStore     TaskStore
+	adminIDs      map[int64]bool
 )
 
 type Config struct {
@@ -31,7 +37,19 @@ type Config struct {
 		User     string
 		Password string
 	}
-	BotToken string
+	BotToken         string
+	Admins           []int64
+	TaskStoreBackend string
+	TaskFilePath     string
+	MetricsAddr      string
+
+	BotMode       string
+	WebhookAddr   string
+	WebhookURL    string
+	WebhookPath   string
+	WebhookSecret string
+	TLSCertPath   string
+	TLSKeyPath    string
 }
 
 type Task struct {
@@ -45,23 +63,71 @@ func main() {
 	// Инициализация конфигурации
 	cfg, err := loadConfig()
 	if err != nil {
-		logger.Fatal("Ошибка загрузки конфигурации:", err)
+		logger.Error("Ошибка загрузки конфигурации", "err", err)
+		os.Exit(1)
 	}
 
 	// Подключение к БД
 	if err = initDB(cfg); err != nil {
-		logger.Fatal("Ошибка инициализации БД:", err)
+		logger.Error("Ошибка инициализации БД", "err", err)
+		os.Exit(1)
 	}
 	defer db.Close()
 
+	adminIDs = make(map[int64]bool, len(cfg.Admins))
+	for _, id := range cfg.Admins {
+		adminIDs[id] = true
+	}
+
+	// Инициализация хранилища задач
+	if err = initTaskStore(cfg); err != nil {
+		logger.Error("Ошибка инициализации хранилища задач", "err", err)
+		os.Exit(1)
+	}
+
 	// Инициализация бота
 	if bot, err = tgbotapi.NewBotAPI(cfg.BotToken); err != nil {
-		logger.Panic("Ошибка инициализации бота:", err)
+		logger.Error("Ошибка инициализации бота", "err", err)
+		panic(err)
+	}
+	logger.Info("Авторизован", "username", bot.Self.UserName)
+
+	// Метрики Prometheus
+	metricsSrv := metrics.Serve(cfg.MetricsAddr)
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Ошибка остановки сервера метрик", "err", err)
+		}
+	}()
+
+	// Запуск обработчика обновлений с остановкой по SIGINT/SIGTERM
+	ctx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	updates, stopUpdates := startUpdatesSource(cfg)
+	processUpdates(ctx, updates, stopUpdates)
+}
+
+// startUpdatesSource поднимает источник обновлений согласно cfg.BotMode:
+// long-polling (по умолчанию) или webhook, и возвращает функцию остановки
+// для processUpdates.
+func startUpdatesSource(cfg *Config) (tgbotapi.UpdatesChannel, func()) {
+	if cfg.BotMode == "webhook" {
+		updates, webhookSrv, closeUpdates := startWebhook(cfg)
+		return updates, func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := webhookSrv.Shutdown(shutdownCtx); err != nil {
+				logger.Error("Ошибка остановки сервера вебхука", "err", err)
+			}
+			closeUpdates()
+		}
 	}
-	logger.Printf("Авторизован как %s", bot.Self.UserName)
 
-	// Запуск обработчика обновлений
-	processUpdates(tgbotapi.NewUpdate(0))
+	updates := bot.GetUpdatesChan(tgbotapi.NewUpdate(0))
+	return updates, bot.StopReceivingUpdates
 }
 
 func loadConfig() (*Config, error) {
@@ -75,11 +141,72 @@ func loadConfig() (*Config, error) {
 	cfg.DB.Name = os.Getenv("DB_NAME")
 	cfg.DB.User = os.Getenv("DB_USER")
 	cfg.DB.Password = os.Getenv("DB_PASSWORD")
-	cfg.BotToken = "7949936274:AAFsZMMLnb-SwGJiQUDXAa0aVd8zNWIzyOA"
+	cfg.BotToken = os.Getenv("TELEGRAM_APITOKEN")
+	if cfg.BotToken == "" {
+		return nil, fmt.Errorf("не задан TELEGRAM_APITOKEN")
+	}
+
+	for _, raw := range strings.Split(os.Getenv("BOT_ADMINS"), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный ID администратора %q: %v", raw, err)
+		}
+		cfg.Admins = append(cfg.Admins, id)
+	}
+
+	cfg.TaskStoreBackend = os.Getenv("TASK_STORE_BACKEND")
+	if cfg.TaskStoreBackend == "" {
+		cfg.TaskStoreBackend = "memory"
+	}
+	cfg.TaskFilePath = os.Getenv("TASK_FILE_PATH")
+
+	cfg.MetricsAddr = os.Getenv("METRICS_ADDR")
+	if cfg.MetricsAddr == "" {
+		cfg.MetricsAddr = ":9090"
+	}
+
+	cfg.BotMode = os.Getenv("BOT_MODE")
+	if cfg.BotMode == "" {
+		cfg.BotMode = "polling"
+	}
+	cfg.WebhookAddr = os.Getenv("WEBHOOK_ADDR")
+	cfg.WebhookURL = os.Getenv("WEBHOOK_URL")
+	cfg.WebhookPath = os.Getenv("WEBHOOK_PATH")
+	if cfg.WebhookPath == "" {
+		cfg.WebhookPath = "/webhook"
+	}
+	cfg.WebhookSecret = os.Getenv("WEBHOOK_SECRET")
+	cfg.TLSCertPath = os.Getenv("TLS_CERT_PATH")
+	cfg.TLSKeyPath = os.Getenv("TLS_KEY_PATH")
 
 	return &cfg, nil
 }
 
+// initTaskStore создаёт реализацию TaskStore согласно cfg.TaskStoreBackend:
+// "memory" (встроенный tasks.yaml), "postgres" (таблицы tasks/task_options)
+// или "hybrid" (файл на диске с перезагрузкой по SIGHUP).
+func initTaskStore(cfg *Config) error {
+	switch cfg.TaskStoreBackend {
+	case "postgres":
+		store := NewPgTaskStore()
+		if err := store.ensureSchema(); err != nil {
+			return err
+		}
+		taskStore = store
+	case "hybrid":
+		store := NewHybridTaskStore(cfg.TaskFilePath)
+		store.WatchReload()
+		taskStore = store
+	default:
+		taskStore = NewMemoryTaskStore()
+	}
+	return taskStore.Load()
+}
+
 func initDB(cfg *Config) error {
 	connStr := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
@@ -111,22 +238,72 @@ func initDB(cfg *Config) error {
 		return fmt.Errorf("ошибка создания таблицы: %v", err)
 	}
 
-	logger.Println("База данных успешно инициализирована")
+	// Столбцы SM-2 добавлены позже и могли отсутствовать в уже
+	// существующей таблице — CREATE TABLE IF NOT EXISTS их не добавит.
+	if _, err = db.ExecContext(ctx, `
+		ALTER TABLE user_progress ADD COLUMN IF NOT EXISTS ease_factor REAL DEFAULT 2.5;
+		ALTER TABLE user_progress ADD COLUMN IF NOT EXISTS interval_days INT DEFAULT 0;
+		ALTER TABLE user_progress ADD COLUMN IF NOT EXISTS repetitions INT DEFAULT 0;
+		ALTER TABLE user_progress ADD COLUMN IF NOT EXISTS due_at TIMESTAMPTZ DEFAULT now();
+		ALTER TABLE user_progress ADD COLUMN IF NOT EXISTS last_quality SMALLINT;
+	`); err != nil {
+		return fmt.Errorf("ошибка миграции таблицы прогресса: %v", err)
+	}
+
+	if err = createUserStateTable(ctx); err != nil {
+		return fmt.Errorf("ошибка создания таблицы состояний: %v", err)
+	}
+
+	if err = createScoresTable(ctx); err != nil {
+		return fmt.Errorf("ошибка создания таблицы очков: %v", err)
+	}
+
+	logger.Info("База данных успешно инициализирована")
 	return nil
 }
 
-func processUpdates(updateConfig tgbotapi.UpdateConfig) {
-	updates := bot.GetUpdatesChan(updateConfig)
-
+// processUpdates обрабатывает обновления (long-polling или webhook) до
+// отмены ctx. По отмене вызывает stop, дочитывает уже полученные из
+// канала обновления и дожидается завершения обработчиков, запущенных на
+// лету.
+func processUpdates(ctx context.Context, updates tgbotapi.UpdatesChannel, stop func()) {
+	go func() {
+		<-ctx.Done()
+		logger.Info("Получен сигнал остановки, прекращаем приём обновлений")
+		stop()
+	}()
+
+	var wg sync.WaitGroup
 	for update := range updates {
-		if update.CallbackQuery != nil {
-			handleCallbackQuery(update.CallbackQuery)
-			continue
-		}
+		update := update
+		metrics.InFlightUpdates.Inc()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer metrics.InFlightUpdates.Dec()
+			handleUpdate(update)
+		}()
+	}
 
-		if update.Message != nil && update.Message.IsCommand() {
-			handleCommand(update.Message)
-		}
+	wg.Wait()
+	logger.Info("Все обработчики обновлений завершены")
+}
+
+func handleUpdate(update tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		handleCallbackQuery(update.CallbackQuery)
+		return
+	}
+
+	if update.Message != nil && update.Message.IsCommand() {
+		metrics.MessagesReceivedTotal.Inc()
+		handleCommand(update.Message)
+		return
+	}
+
+	if update.Message != nil && update.Message.Text != "" {
+		metrics.MessagesReceivedTotal.Inc()
+		dispatchMessage(update.Message)
 	}
 }
 
@@ -138,6 +315,20 @@ func handleCommand(msg *tgbotapi.Message) {
 		handleTaskCommand(msg.Chat.ID)
 	case "progress":
 		showProgress(msg.Chat.ID)
+	case "skip":
+		handleSkipCommand(msg.Chat.ID)
+	case "due":
+		handleDueCommand(msg.Chat.ID)
+	case "cancel":
+		handleCancelCommand(msg.Chat.ID)
+	case "addtask":
+		handleAddTaskCommand(msg)
+	case "quiz":
+		handleQuizCommand(msg)
+	case "leaderboard":
+		handleLeaderboardCommand(msg.Chat.ID)
+	case "streak":
+		handleStreakCommand(msg.Chat.ID, msg.From.ID)
 	default:
 		sendMessage(msg.Chat.ID, "Неизвестная команда 🤷")
 	}
@@ -153,15 +344,24 @@ func handleTaskCommand(chatID int64) {
 }
 
 func getNextTask(userID int64) *Task {
+	dueTaskID, err := getDueTaskID(userID)
+	if err != nil {
+		logger.Error("Ошибка получения задач к повторению", "err", err)
+		return nil
+	}
+	if dueTaskID != 0 {
+		return findTask(dueTaskID)
+	}
+
 	progress, err := getUserProgress(userID)
 	if err != nil {
-		logger.Printf("Ошибка получения прогресса: %v", err)
+		logger.Error("Ошибка получения прогресса", "err", err)
 		return nil
 	}
 
 	tasks := getTasks()
 	for i := range tasks {
-		if solved, exists := progress[tasks[i].ID]; !exists || !solved {
+		if _, seen := progress[tasks[i].ID]; !seen {
 			return &tasks[i]
 		}
 	}
@@ -180,37 +380,68 @@ func sendTask(chatID int64, task *Task) {
 	msg.ReplyMarkup = keyboard
 
 	if _, err := bot.Send(msg); err != nil {
-		logger.Printf("Ошибка отправки задачи: %v", err)
+		logger.Error("Ошибка отправки задачи", "err", err)
+	}
+	metrics.TasksSentTotal.Inc()
+
+	if err := saveUserState(&UserState{UserID: chatID, PosTag: PosTagAwaitingAnswer, CurrentTaskID: task.ID}); err != nil {
+		logger.Error("Ошибка сохранения состояния", "err", err)
 	}
 }
 
 func handleCallbackQuery(query *tgbotapi.CallbackQuery) {
+	userID := query.From.ID
+
+	if !allowCallback(int64(userID)) {
+		if _, err := bot.Request(tgbotapi.NewCallback(query.ID, "Слишком часто, подождите немного ⏳")); err != nil {
+			logger.Error("Ошибка обработки callback", "err", err)
+		}
+		return
+	}
+
+	if query.Data == "skip_confirm" || query.Data == "skip_cancel" {
+		handleSkipCallback(query)
+		return
+	}
+
+	if query.Message != nil {
+		if sessionVal, ok := quizSessions.Load(query.Message.Chat.ID); ok {
+			handleQuizCallback(query, sessionVal.(*quizSession))
+			return
+		}
+	}
+
 	parts := strings.SplitN(query.Data, ":", 2)
 	if len(parts) != 2 {
-		logger.Printf("Некорректный callback: %s", query.Data)
+		logger.Warn("Некорректный callback", "data", query.Data)
 		return
 	}
 
 	taskID, err := strconv.Atoi(parts[0])
 	if err != nil {
-		logger.Printf("Ошибка парсинга taskID: %v", err)
+		logger.Error("Ошибка парсинга taskID", "err", err)
 		return
 	}
 
 	task := findTask(taskID)
 	if task == nil {
-		logger.Printf("Задача %d не найдена", taskID)
+		logger.Warn("Задача не найдена", "task_id", taskID)
 		return
 	}
 
-	userID := query.From.ID
-	answerCorrect := parts[1] == task.Answer
+	state, err := getUserState(int64(userID))
+	if err != nil {
+		logger.Error("Ошибка получения состояния пользователя", "err", err)
+		state = &UserState{UserID: int64(userID), CurrentTaskID: task.ID}
+	}
 
-	// Обновление прогресса
+	// Проверка и сохранение ответа — через ту же функцию перехода, что
+	// и message-путь в handleAwaitingAnswer.
+	answerCorrect := submitAnswer(int64(userID), int64(userID), query.From.UserName, task, parts[1], state)
 	if answerCorrect {
-		if err := saveUserProgress(int64(userID), task.ID, true); err != nil {
-			logger.Printf("Ошибка сохранения прогресса: %v", err)
-		}
+		metrics.CallbacksTotal.WithLabelValues("correct").Inc()
+	} else {
+		metrics.CallbacksTotal.WithLabelValues("incorrect").Inc()
 	}
 
 	// Отправка ответа
@@ -222,7 +453,7 @@ func handleCallbackQuery(query *tgbotapi.CallbackQuery) {
 	}
 
 	if _, err := bot.Request(callbackCfg); err != nil {
-		logger.Printf("Ошибка обработки callback: %v", err)
+		logger.Error("Ошибка обработки callback", "err", err)
 	}
 
 	// Обновление сообщения или отправка следующей задачи
@@ -235,23 +466,19 @@ func handleCallbackQuery(query *tgbotapi.CallbackQuery) {
 	}
 }
 
+// findTask ищет задачу по ID через TaskStore (O(1) по внутреннему индексу),
+// вместо прежнего линейного прохода по свежевыделенному срезу.
 func findTask(taskID int) *Task {
-	for i := range getTasks() {
-		if getTasks()[i].ID == taskID {
-			return &getTasks()[i]
-		}
+	task, ok := taskStore.ByID(taskID)
+	if !ok {
+		return nil
 	}
-	return nil
+	return task
 }
 
+// getTasks возвращает все задачи из текущего TaskStore.
 func getTasks() []Task {
-	return []Task{
-		{ID: 1, Text: "Какой оператор используется для объявления переменной в Go?", Answer: "var", Options: []string{"let", "const", "var", "define"}},
-		{ID: 2, Text: "Какой тип данных используется для целых чисел в Go?", Answer: "int", Options: []string{"integer", "float", "int", "number"}},
-		{ID: 3, Text: "Какой тип данных используется для строк в Go?", Answer: "string", Options: []string{"char", "string", "text", "varchar"}},
-		{ID: 4, Text: "Какая директива используется для импорта пакетов в Go?", Answer: "import", Options: []string{"include", "import", "use", "require"}},
-		{ID: 5, Text: "Что выводит команда fmt.Println(1+1) в Go?", Answer: "2", Options: []string{"1", "2", "3", "Ошибка"}},
-	}
+	return taskStore.All()
 }
 
 func sendWelcome(chatID int64) {
@@ -259,14 +486,20 @@ func sendWelcome(chatID int64) {
 
 Используйте команды:
 /task - Получить новую задачу
-/progress - Показать прогресс`
+/progress - Показать прогресс
+/skip - Пропустить текущую задачу
+/cancel - Отменить текущий диалог
+/due - Показать расписание повторений
+/quiz - Начать викторину в групповом чате
+/leaderboard - Таблица лидеров чата
+/streak - Ваша серия дней подряд`
 	sendMessage(chatID, text)
 }
 
 func showProgress(chatID int64) {
 	progress, err := getUserProgress(chatID)
 	if err != nil {
-		logger.Printf("Ошибка получения прогресса: %v", err)
+		logger.Error("Ошибка получения прогресса", "err", err)
 		sendMessage(chatID, "Ошибка получения прогресса 😕")
 		return
 	}
@@ -279,8 +512,13 @@ func showProgress(chatID int64) {
 		}
 	}
 
-	text := fmt.Sprintf("Ваш прогресс: 📊\n\nРешено задач: %d/%d\nПрогресс: %.1f%%",
-		solved, total, float64(solved)/float64(total)*100)
+	streak, err := getUserStreak(chatID, chatID)
+	if err != nil {
+		logger.Error("Ошибка получения серии", "err", err)
+	}
+
+	text := fmt.Sprintf("Ваш прогресс: 📊\n\nРешено задач: %d/%d\nПрогресс: %.1f%%\nСерия дней подряд: %d 🔥",
+		solved, total, float64(solved)/float64(total)*100, streak)
 	sendMessage(chatID, text)
 }
 
@@ -293,8 +531,10 @@ func getUserProgress(userID int64) (map[int]bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	queryStart := time.Now()
 	rows, err := db.QueryContext(ctx,
 		"SELECT task_id, solved FROM user_progress WHERE user_id = $1", userID)
+	metrics.DBQueryDuration.Observe(time.Since(queryStart).Seconds())
 	if err != nil {
 		return nil, err
 	}
@@ -315,17 +555,10 @@ func getUserProgress(userID int64) (map[int]bool, error) {
 	return progress, nil
 }
 
-func saveUserProgress(userID int64, taskID int, solved bool) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	_, err := db.ExecContext(ctx,
-		`INSERT INTO user_progress (user_id, task_id, solved)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id, task_id)
-		DO UPDATE SET solved = $3`,
-		userID, taskID, solved,
-	)
+// saveUserProgress фиксирует ответ пользователя и продвигает расписание
+// повторений по алгоритму SM-2 (см. recordAnswer в scheduler.go).
+func saveUserProgress(userID int64, taskID int, correct bool, attempts int) error {
+	err := recordAnswer(userID, taskID, correct, attempts)
 
 	// Сброс кэша при обновлении
 	progressCache.Delete(userID)
@@ -335,6 +568,6 @@ func saveUserProgress(userID int64, taskID int, solved bool) error {
 func sendMessage(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	if _, err := bot.Send(msg); err != nil {
-		logger.Printf("Ошибка отправки сообщения: %v", err)
+		logger.Error("Ошибка отправки сообщения", "err", err)
 	}
 }
@@ -0,0 +1,71 @@
+// Package metrics предоставляет счётчики Prometheus бота и HTTP-сервер,
+// публикующий их на /metrics.
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// MessagesReceivedTotal считает входящие текстовые сообщения и команды.
+	MessagesReceivedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "messages_received_total",
+		Help: "Общее количество полученных сообщений.",
+	})
+
+	// CallbacksTotal считает обработанные callback-ответы по результату
+	// ("correct" / "incorrect").
+	CallbacksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "callbacks_total",
+		Help: "Количество обработанных callback-ответов по результату.",
+	}, []string{"result"})
+
+	// TasksSentTotal считает отправленные пользователям задачи.
+	TasksSentTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tasks_sent_total",
+		Help: "Общее количество отправленных задач.",
+	})
+
+	// DBQueryDuration измеряет длительность запросов к БД.
+	DBQueryDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Длительность запросов к базе данных.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// InFlightUpdates отражает число обновлений, обрабатываемых в данный момент.
+	InFlightUpdates = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "in_flight_updates",
+		Help: "Количество обновлений Telegram, обрабатываемых в данный момент.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		MessagesReceivedTotal,
+		CallbacksTotal,
+		TasksSentTotal,
+		DBQueryDuration,
+		InFlightUpdates,
+	)
+}
+
+// Serve запускает HTTP-сервер с /metrics на addr и сразу возвращает его,
+// не дожидаясь завершения; вызывающий код останавливает сервер через
+// (*http.Server).Shutdown.
+func Serve(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("Ошибка сервера метрик", "err", err)
+		}
+	}()
+	return srv
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// quizSession отслеживает текущий вопрос групповой викторины и то, кто из
+// участников уже ответил правильно первым. mu защищает Answered и asked
+// от гонки между goroutine'ами, запускаемыми processUpdates на каждое
+// обновление.
+type quizSession struct {
+	mu       sync.Mutex
+	TaskID   int
+	Answered bool
+	asked    map[int]bool
+}
+
+// quizSessions хранит активную сессию викторины по ID чата.
+var quizSessions sync.Map
+
+func isGroupChat(chat *tgbotapi.Chat) bool {
+	return chat.IsGroup() || chat.IsSuperGroup()
+}
+
+// handleQuizCommand запускает групповую викторину: бот рассылает задачи в
+// чат, и первый участник, давший верный ответ на callback, получает очко.
+func handleQuizCommand(msg *tgbotapi.Message) {
+	if !isGroupChat(msg.Chat) {
+		sendMessage(msg.Chat.ID, "/quiz доступен только в групповых чатах.")
+		return
+	}
+	broadcastNextQuizTask(msg.Chat.ID)
+}
+
+func broadcastNextQuizTask(chatID int64) {
+	prev, _ := quizSessions.Load(chatID)
+	asked := map[int]bool{}
+	if session, ok := prev.(*quizSession); ok {
+		// Копия под mu: новая сессия получает свою карту, и запись ниже не
+		// затрагивает карту предыдущей сессии.
+		session.mu.Lock()
+		for id := range session.asked {
+			asked[id] = true
+		}
+		session.mu.Unlock()
+	}
+
+	task := pickQuizTask(asked)
+	if task == nil {
+		sendMessage(chatID, "Задачи для викторины закончились 🏁")
+		quizSessions.Delete(chatID)
+		return
+	}
+
+	asked[task.ID] = true
+	quizSessions.Store(chatID, &quizSession{TaskID: task.ID, asked: asked})
+	sendTask(chatID, task)
+}
+
+// pickQuizTask возвращает первую ещё не заданную в этой сессии задачу.
+func pickQuizTask(asked map[int]bool) *Task {
+	tasks := getTasks()
+	for i := range tasks {
+		if !asked[tasks[i].ID] {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+// handleQuizCallback обрабатывает ответ на вопрос групповой викторины:
+// только первый верный ответ засчитывается и приносит очко.
+func handleQuizCallback(query *tgbotapi.CallbackQuery, session *quizSession) {
+	chatID := query.Message.Chat.ID
+
+	parts := strings.SplitN(query.Data, ":", 2)
+	if len(parts) != 2 {
+		logger.Warn("Некорректный callback викторины", "data", query.Data)
+		return
+	}
+
+	taskID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		logger.Error("Ошибка парсинга taskID викторины", "err", err)
+		return
+	}
+
+	callbackCfg := tgbotapi.NewCallback(query.ID, "")
+	defer func() {
+		if _, err := bot.Request(callbackCfg); err != nil {
+			logger.Error("Ошибка обработки callback викторины", "err", err)
+		}
+	}()
+
+	if taskID != session.TaskID {
+		callbackCfg.Text = "Вопрос уже завершён"
+		return
+	}
+
+	task := findTask(taskID)
+	if task == nil {
+		callbackCfg.Text = "Задача не найдена"
+		return
+	}
+
+	if !matchesAnswer(parts[1], task.Answer) {
+		callbackCfg.Text = "Неверно ❌"
+		return
+	}
+
+	// Атомарная проверка-и-установка Answered: только один из
+	// одновременно нажавших верный ответ должен пройти дальше.
+	session.mu.Lock()
+	wasAnswered := session.Answered
+	session.Answered = true
+	session.mu.Unlock()
+	if wasAnswered {
+		callbackCfg.Text = "Вопрос уже завершён"
+		return
+	}
+
+	callbackCfg.Text = "Верно! Вы первый ✅"
+
+	username := query.From.UserName
+	if err := recordActivity(chatID, int64(query.From.ID), username, 1); err != nil {
+		logger.Error("Ошибка начисления очков", "err", err)
+	}
+
+	sendMessage(chatID, fmt.Sprintf("🏆 %s первым ответил правильно на задачу #%d!", displayName(query.From), task.ID))
+	broadcastNextQuizTask(chatID)
+}
+
+// displayName возвращает username пользователя, либо его имя, если
+// username не задан.
+func displayName(user *tgbotapi.User) string {
+	if user.UserName != "" {
+		return "@" + user.UserName
+	}
+	return user.FirstName
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// callbackLimiters хранит per-user токен-бакеты, ограничивающие частоту
+// обработки callback-ответов (защита от спам-кликов).
+var callbackLimiters sync.Map
+
+const (
+	callbackRateLimit = 1 // запросов в секунду
+	callbackBurst     = 5
+)
+
+// allowCallback возвращает false, если пользователь превысил лимит
+// 1 сообщение/сек с запасом в 5 (token bucket).
+func allowCallback(userID int64) bool {
+	limiterVal, _ := callbackLimiters.LoadOrStore(userID, rate.NewLimiter(callbackRateLimit, callbackBurst))
+	return limiterVal.(*rate.Limiter).Allow()
+}
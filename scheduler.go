@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Kolya0001/bot-Tg/metrics"
+)
+
+// minEaseFactor — нижняя граница фактора лёгкости в алгоритме SM-2.
+const minEaseFactor = 1.3
+
+// progressEntry отражает строку таблицы user_progress, используемую
+// планировщиком повторений.
+type progressEntry struct {
+	Solved       bool
+	EaseFactor   float64
+	IntervalDays int
+	Repetitions  int
+	LastQuality  int
+}
+
+// computeQuality переводит результат ответа в оценку SM-2 (0..5):
+// неверный ответ — 2, верный с первой попытки — 5, верный после повторных
+// попыток — 3 или 4 в зависимости от их числа.
+func computeQuality(correct bool, attempts int) int {
+	if !correct {
+		return 2
+	}
+	switch {
+	case attempts <= 0:
+		return 5
+	case attempts == 1:
+		return 4
+	default:
+		return 3
+	}
+}
+
+// applySM2 обновляет EaseFactor/IntervalDays/Repetitions по рекурренте SM-2
+// для оценки quality (0..5).
+func applySM2(entry *progressEntry, quality int) {
+	if quality < 3 {
+		entry.Repetitions = 0
+		entry.IntervalDays = 1
+	} else {
+		entry.Repetitions++
+		switch entry.Repetitions {
+		case 1:
+			entry.IntervalDays = 1
+		case 2:
+			entry.IntervalDays = 6
+		default:
+			entry.IntervalDays = int(math.Round(float64(entry.IntervalDays) * entry.EaseFactor))
+		}
+	}
+
+	q := float64(quality)
+	entry.EaseFactor += 0.1 - (5-q)*(0.08+(5-q)*0.02)
+	if entry.EaseFactor < minEaseFactor {
+		entry.EaseFactor = minEaseFactor
+	}
+	entry.LastQuality = quality
+}
+
+// recordAnswer сохраняет результат ответа на задачу и пересчитывает
+// расписание следующего повторения по SM-2.
+func recordAnswer(userID int64, taskID int, correct bool, attempts int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	entry := progressEntry{EaseFactor: 2.5}
+	row := db.QueryRowContext(ctx,
+		"SELECT solved, ease_factor, interval_days, repetitions FROM user_progress WHERE user_id = $1 AND task_id = $2",
+		userID, taskID)
+	if err := row.Scan(&entry.Solved, &entry.EaseFactor, &entry.IntervalDays, &entry.Repetitions); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	quality := computeQuality(correct, attempts)
+	applySM2(&entry, quality)
+	entry.Solved = entry.Solved || correct
+
+	queryStart := time.Now()
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO user_progress (user_id, task_id, solved, ease_factor, interval_days, repetitions, due_at, last_quality)
+		VALUES ($1, $2, $3, $4, $5, $6, now() + ($5::text || ' days')::interval, $7)
+		ON CONFLICT (user_id, task_id)
+		DO UPDATE SET solved = $3, ease_factor = $4, interval_days = $5, repetitions = $6,
+			due_at = now() + ($5::text || ' days')::interval, last_quality = $7`,
+		userID, taskID, entry.Solved, entry.EaseFactor, entry.IntervalDays, entry.Repetitions, entry.LastQuality,
+	)
+	metrics.DBQueryDuration.Observe(time.Since(queryStart).Seconds())
+	return err
+}
+
+// getDueTaskID возвращает ID задачи с ближайшим наступившим сроком
+// повторения, либо 0, если таких нет.
+func getDueTaskID(userID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var taskID int
+	row := db.QueryRowContext(ctx,
+		"SELECT task_id FROM user_progress WHERE user_id = $1 AND due_at <= now() ORDER BY due_at ASC LIMIT 1",
+		userID)
+	if err := row.Scan(&taskID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return taskID, nil
+}
+
+// handleDueCommand показывает пользователю время следующего повторения
+// по каждой из начатых задач.
+func handleDueCommand(chatID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT task_id, due_at FROM user_progress WHERE user_id = $1 ORDER BY due_at ASC", chatID)
+	if err != nil {
+		logger.Error("Ошибка получения расписания повторений", "err", err)
+		sendMessage(chatID, "Ошибка получения расписания повторений 😕")
+		return
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var taskID int
+		var dueAt time.Time
+		if err := rows.Scan(&taskID, &dueAt); err != nil {
+			logger.Error("Ошибка чтения расписания повторений", "err", err)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("Задача #%d — %s", taskID, dueAt.Format("2006-01-02 15:04")))
+	}
+
+	if len(lines) == 0 {
+		sendMessage(chatID, "Пока нет задач с запланированным повторением.")
+		return
+	}
+
+	sendMessage(chatID, "Расписание повторений: 📅\n\n"+strings.Join(lines, "\n"))
+}
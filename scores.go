@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ScoreEntry — строка таблицы scores: очки и серия дней подряд пользователя в
+// рамках конкретного чата (личного или группового).
+type ScoreEntry struct {
+	UserID   int64
+	Username string
+	Points   int
+	Streak   int
+}
+
+func createScoresTable(ctx context.Context) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS scores (
+			chat_id BIGINT,
+			user_id BIGINT,
+			username TEXT,
+			points INTEGER NOT NULL DEFAULT 0,
+			streak INTEGER NOT NULL DEFAULT 0,
+			last_answer_at TIMESTAMPTZ,
+			PRIMARY KEY (chat_id, user_id)
+		);
+	`)
+	return err
+}
+
+// recordActivity начисляет очки за правильный ответ и обновляет серию
+// дней подряд: серия растёт при ответе на следующий день после
+// предыдущего, сбрасывается до 1 при пропуске дня и не меняется при
+// повторном ответе в тот же день.
+func recordActivity(chatID, userID int64, username string, points int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO scores (chat_id, user_id, username, points, streak, last_answer_at)
+		VALUES ($1, $2, $3, $4, 1, now())
+		ON CONFLICT (chat_id, user_id)
+		DO UPDATE SET
+			username = $3,
+			points = scores.points + $4,
+			streak = CASE
+				WHEN scores.last_answer_at::date = now()::date THEN scores.streak
+				WHEN scores.last_answer_at::date = (now()::date - INTERVAL '1 day') THEN scores.streak + 1
+				ELSE 1
+			END,
+			last_answer_at = now()`,
+		chatID, userID, username, points,
+	)
+	return err
+}
+
+// getLeaderboard возвращает топ-N пользователей чата по очкам.
+func getLeaderboard(chatID int64, limit int) ([]ScoreEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT user_id, username, points, streak FROM scores WHERE chat_id = $1 ORDER BY points DESC LIMIT $2",
+		chatID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ScoreEntry
+	for rows.Next() {
+		var e ScoreEntry
+		if err := rows.Scan(&e.UserID, &e.Username, &e.Points, &e.Streak); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// getUserStreak возвращает текущую серию пользователя в чате (0, если
+// активности ещё не было).
+func getUserStreak(chatID, userID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var streak int
+	row := db.QueryRowContext(ctx,
+		"SELECT streak FROM scores WHERE chat_id = $1 AND user_id = $2", chatID, userID)
+	if err := row.Scan(&streak); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return streak, nil
+}
+
+// handleLeaderboardCommand показывает топ-10 пользователей текущего чата.
+func handleLeaderboardCommand(chatID int64) {
+	entries, err := getLeaderboard(chatID, 10)
+	if err != nil {
+		logger.Error("Ошибка получения таблицы лидеров", "err", err)
+		sendMessage(chatID, "Ошибка получения таблицы лидеров 😕")
+		return
+	}
+
+	if len(entries) == 0 {
+		sendMessage(chatID, "Пока никто не набрал очков в этом чате.")
+		return
+	}
+
+	var lines []string
+	for i, e := range entries {
+		name := e.Username
+		if name == "" {
+			name = fmt.Sprintf("ID %d", e.UserID)
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s — %d очков (серия: %d)", i+1, name, e.Points, e.Streak))
+	}
+
+	sendMessage(chatID, "Таблица лидеров: 🏆\n\n"+strings.Join(lines, "\n"))
+}
+
+// handleStreakCommand показывает серию дней подряд вызвавшего команду
+// пользователя.
+func handleStreakCommand(chatID, userID int64) {
+	streak, err := getUserStreak(chatID, userID)
+	if err != nil {
+		logger.Error("Ошибка получения серии", "err", err)
+		sendMessage(chatID, "Ошибка получения серии 😕")
+		return
+	}
+	sendMessage(chatID, fmt.Sprintf("Ваша текущая серия: %d 🔥", streak))
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// PosTag описывает текущий шаг пользователя в диалоге с ботом,
+// по аналогии с подходом l9_stud_bot.
+type PosTag string
+
+const (
+	PosTagNone             PosTag = "none"
+	PosTagAwaitingAnswer   PosTag = "awaiting_answer"
+	PosTagConfirmSkip      PosTag = "confirm_skip"
+	PosTagReviewMode       PosTag = "review_mode"
+	PosTagAddingCustomTask PosTag = "adding_custom_task"
+)
+
+// UserState хранит состояние диалога конкретного пользователя.
+type UserState struct {
+	UserID        int64
+	PosTag        PosTag
+	CurrentTaskID int
+	Attempts      int
+	UpdatedAt     time.Time
+}
+
+// Handler обрабатывает входящее текстовое сообщение в рамках текущего состояния.
+type Handler func(msg *tgbotapi.Message, state *UserState)
+
+// handlers сопоставляет PosTag обработчику, отвечающему за этот шаг диалога.
+var handlers = map[PosTag]Handler{
+	PosTagAwaitingAnswer:   handleAwaitingAnswer,
+	PosTagConfirmSkip:      handleConfirmSkipText,
+	PosTagAddingCustomTask: handleAddingCustomTask,
+}
+
+func createUserStateTable(ctx context.Context) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS user_state (
+			user_id BIGINT PRIMARY KEY,
+			pos_tag TEXT NOT NULL DEFAULT 'none',
+			current_task_id INTEGER NOT NULL DEFAULT 0,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+	`)
+	return err
+}
+
+// getUserState возвращает текущее состояние пользователя, либо состояние по
+// умолчанию (PosTagNone), если запись ещё не создана.
+func getUserState(userID int64) (*UserState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	state := &UserState{UserID: userID, PosTag: PosTagNone}
+
+	var posTag string
+	row := db.QueryRowContext(ctx,
+		"SELECT pos_tag, current_task_id, attempts, updated_at FROM user_state WHERE user_id = $1", userID)
+	if err := row.Scan(&posTag, &state.CurrentTaskID, &state.Attempts, &state.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return state, nil
+		}
+		return nil, err
+	}
+	state.PosTag = PosTag(posTag)
+	return state, nil
+}
+
+// saveUserState сохраняет состояние пользователя (upsert).
+func saveUserState(state *UserState) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO user_state (user_id, pos_tag, current_task_id, attempts, updated_at)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (user_id)
+		DO UPDATE SET pos_tag = $2, current_task_id = $3, attempts = $4, updated_at = now()`,
+		state.UserID, string(state.PosTag), state.CurrentTaskID, state.Attempts,
+	)
+	return err
+}
+
+// resetUserState сбрасывает состояние пользователя в PosTagNone (используется /cancel).
+func resetUserState(userID int64) error {
+	return saveUserState(&UserState{UserID: userID, PosTag: PosTagNone})
+}
+
+// dispatchMessage проводит входящее не-командное сообщение через конечный
+// автомат состояний: и callback-путь, и message-путь используют одну и ту же
+// функцию перехода, чтобы добавление новых типов вопросов не трогало
+// верхнеуровневый цикл обновлений.
+func dispatchMessage(msg *tgbotapi.Message) {
+	state, err := getUserState(msg.Chat.ID)
+	if err != nil {
+		logger.Error("Ошибка получения состояния пользователя", "err", err)
+		return
+	}
+
+	handler, ok := handlers[state.PosTag]
+	if !ok {
+		return
+	}
+	handler(msg, state)
+}
+
+// handleAwaitingAnswer обрабатывает свободный текстовый ответ на задачу.
+func handleAwaitingAnswer(msg *tgbotapi.Message, state *UserState) {
+	task := findTask(state.CurrentTaskID)
+	if task == nil {
+		resetUserState(state.UserID)
+		return
+	}
+
+	if !submitAnswer(msg.Chat.ID, msg.Chat.ID, msg.From.UserName, task, msg.Text, state) {
+		sendMessage(msg.Chat.ID, "Неверно ❌ Попробуйте еще раз, или введите /skip")
+		return
+	}
+
+	sendMessage(msg.Chat.ID, "Правильно! ✅")
+	advanceToNextTask(msg.Chat.ID)
+}
+
+// submitAnswer проверяет ответ пользователя на задачу и обновляет его
+// прогресс и состояние. И callback-путь (handleCallbackQuery), и
+// message-путь (handleAwaitingAnswer) вызывают эту функцию, чтобы
+// добавление новых типов вопросов не требовало правки обоих мест.
+func submitAnswer(chatID, userID int64, username string, task *Task, answerText string, state *UserState) bool {
+	if !matchesAnswer(answerText, task.Answer) {
+		state.Attempts++
+		if err := saveUserState(state); err != nil {
+			logger.Error("Ошибка сохранения состояния", "err", err)
+		}
+		if err := saveUserProgress(userID, task.ID, false, state.Attempts); err != nil {
+			logger.Error("Ошибка сохранения прогресса", "err", err)
+		}
+		return false
+	}
+
+	if err := saveUserProgress(userID, task.ID, true, state.Attempts); err != nil {
+		logger.Error("Ошибка сохранения прогресса", "err", err)
+	}
+	if err := recordActivity(chatID, userID, username, 1); err != nil {
+		logger.Error("Ошибка обновления серии", "err", err)
+	}
+	return true
+}
+
+// handleConfirmSkipText позволяет подтвердить /skip текстом "да"/"нет", на
+// случай если пользователь не нажал inline-кнопку.
+func handleConfirmSkipText(msg *tgbotapi.Message, state *UserState) {
+	switch strings.ToLower(strings.TrimSpace(msg.Text)) {
+	case "да", "yes":
+		skipCurrentTask(msg.Chat.ID, state)
+	case "нет", "no":
+		resumeAwaitingAnswer(msg.Chat.ID, state)
+	default:
+		sendMessage(msg.Chat.ID, "Пропустить текущую задачу? Ответьте \"да\" или \"нет\".")
+	}
+}
+
+// matchesAnswer сравнивает ответ пользователя с эталонным без учёта регистра,
+// либо как regexp, если эталон задан в виде /pattern/.
+func matchesAnswer(got, want string) bool {
+	got = strings.TrimSpace(got)
+	if strings.HasPrefix(want, "/") && strings.HasSuffix(want, "/") && len(want) > 1 {
+		pattern := want[1 : len(want)-1]
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			logger.Warn("Некорректное регулярное выражение ответа", "pattern", want, "err", err)
+			return strings.EqualFold(got, want)
+		}
+		return re.MatchString(got)
+	}
+	return strings.EqualFold(got, want)
+}
+
+// advanceToNextTask отправляет пользователю следующую задачу или сообщение
+// о завершении, обновляя состояние.
+func advanceToNextTask(chatID int64) {
+	if nextTask := getNextTask(chatID); nextTask != nil {
+		sendTask(chatID, nextTask)
+		return
+	}
+	sendMessage(chatID, "🎉 Вы решили все доступные задачи!")
+	resetUserState(chatID)
+}
+
+func resumeAwaitingAnswer(chatID int64, state *UserState) {
+	state.PosTag = PosTagAwaitingAnswer
+	if err := saveUserState(state); err != nil {
+		logger.Error("Ошибка сохранения состояния", "err", err)
+	}
+	sendMessage(chatID, "Хорошо, продолжайте отвечать на текущую задачу.")
+}
+
+// skipCurrentTask засчитывает пропуск как неверный ответ: задача получает
+// запись в user_progress и вернётся по расписанию повторений, а
+// getNextTask не выдаёт её снова сразу же.
+func skipCurrentTask(chatID int64, state *UserState) {
+	if state.CurrentTaskID != 0 {
+		if err := saveUserProgress(chatID, state.CurrentTaskID, false, state.Attempts); err != nil {
+			logger.Error("Ошибка сохранения прогресса", "err", err)
+		}
+	}
+	advanceToNextTask(chatID)
+}
+
+// handleSkipCommand запускает подтверждение пропуска текущей задачи через
+// inline-клавиатуру.
+func handleSkipCommand(chatID int64) {
+	state, err := getUserState(chatID)
+	if err != nil || state.CurrentTaskID == 0 {
+		sendMessage(chatID, "Сейчас нет активной задачи для пропуска.")
+		return
+	}
+
+	state.PosTag = PosTagConfirmSkip
+	if err := saveUserState(state); err != nil {
+		logger.Error("Ошибка сохранения состояния", "err", err)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Да, пропустить", "skip_confirm"),
+			tgbotapi.NewInlineKeyboardButtonData("Нет, остаться", "skip_cancel"),
+		),
+	)
+	msg := tgbotapi.NewMessage(chatID, "Пропустить текущую задачу?")
+	msg.ReplyMarkup = keyboard
+	if _, err := bot.Send(msg); err != nil {
+		logger.Error("Ошибка отправки подтверждения пропуска", "err", err)
+	}
+}
+
+// handleSkipCallback обрабатывает нажатие inline-кнопки подтверждения /skip.
+func handleSkipCallback(query *tgbotapi.CallbackQuery) {
+	chatID := int64(query.From.ID)
+
+	callbackCfg := tgbotapi.NewCallback(query.ID, "")
+	if _, err := bot.Request(callbackCfg); err != nil {
+		logger.Error("Ошибка обработки callback", "err", err)
+	}
+
+	state, err := getUserState(chatID)
+	if err != nil {
+		logger.Error("Ошибка получения состояния пользователя", "err", err)
+		return
+	}
+
+	if query.Data == "skip_confirm" {
+		skipCurrentTask(chatID, state)
+		return
+	}
+	resumeAwaitingAnswer(chatID, state)
+}
+
+// handleCancelCommand сбрасывает состояние диалога пользователя.
+func handleCancelCommand(chatID int64) {
+	if err := resetUserState(chatID); err != nil {
+		logger.Error("Ошибка сброса состояния", "err", err)
+		return
+	}
+	sendMessage(chatID, "Диалог сброшен. Используйте /task, чтобы начать заново.")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed tasks.yaml
+var embeddedTasksYAML []byte
+
+// TaskStore абстрагирует хранение и поиск задач, позволяя подменять
+// источник (встроенный YAML, БД, файл с горячей перезагрузкой) без
+// изменения кода обработчиков.
+type TaskStore interface {
+	// Load (пере)загружает набор задач из источника.
+	Load() error
+	// All возвращает все текущие задачи.
+	All() []Task
+	// ByID ищет задачу по ID.
+	ByID(id int) (*Task, bool)
+	// Add добавляет новую задачу и сохраняет её в источнике.
+	Add(task Task) error
+	// Version возвращает номер текущей версии набора задач, растущий
+	// при каждой успешной перезагрузке или добавлении.
+	Version() int
+}
+
+type yamlTask struct {
+	ID      int      `yaml:"id"`
+	Text    string   `yaml:"text"`
+	Answer  string   `yaml:"answer"`
+	Options []string `yaml:"options"`
+}
+
+// MemoryTaskStore хранит задачи в памяти, загружая их из встроенного
+// tasks.yaml, и поддерживает индекс по ID для поиска за O(1).
+type MemoryTaskStore struct {
+	mu      sync.RWMutex
+	tasks   []Task
+	byID    map[int]*Task
+	version int
+}
+
+func NewMemoryTaskStore() *MemoryTaskStore {
+	return &MemoryTaskStore{}
+}
+
+func (s *MemoryTaskStore) Load() error {
+	return s.loadFromYAML(embeddedTasksYAML)
+}
+
+func (s *MemoryTaskStore) loadFromYAML(data []byte) error {
+	var raw []yamlTask
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("ошибка разбора tasks.yaml: %v", err)
+	}
+
+	tasks := make([]Task, len(raw))
+	byID := make(map[int]*Task, len(raw))
+	for i, rt := range raw {
+		tasks[i] = Task{ID: rt.ID, Text: rt.Text, Answer: rt.Answer, Options: rt.Options}
+		byID[rt.ID] = &tasks[i]
+	}
+
+	s.mu.Lock()
+	s.tasks = tasks
+	s.byID = byID
+	s.version++
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryTaskStore) All() []Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tasks
+}
+
+func (s *MemoryTaskStore) ByID(id int) (*Task, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.byID[id]
+	return t, ok
+}
+
+func (s *MemoryTaskStore) Add(task Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks = append(s.tasks, task)
+	s.byID[task.ID] = &s.tasks[len(s.tasks)-1]
+	s.version++
+	return nil
+}
+
+func (s *MemoryTaskStore) Version() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// nextTaskID возвращает следующий свободный ID для новой задачи.
+func nextTaskID(tasks []Task) int {
+	max := 0
+	for _, t := range tasks {
+		if t.ID > max {
+			max = t.ID
+		}
+	}
+	return max + 1
+}
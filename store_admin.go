@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// addTaskWizardStep — шаг мастера добавления задачи /addtask.
+type addTaskWizardStep int
+
+const (
+	wizardStepText addTaskWizardStep = iota
+	wizardStepOptions
+	wizardStepAnswer
+)
+
+// taskDraft накапливает данные задачи между сообщениями мастера /addtask.
+type taskDraft struct {
+	Step    addTaskWizardStep
+	Text    string
+	Options []string
+}
+
+// addTaskDrafts хранит черновики по ID пользователя на время прохождения
+// мастера; в персистентную user_state попадает только текущий PosTag.
+var addTaskDrafts sync.Map
+
+// isAdmin проверяет, входит ли пользователь в BOT_ADMINS.
+func isAdmin(userID int64) bool {
+	return adminIDs[userID]
+}
+
+// handleAddTaskCommand запускает мастер добавления задачи для
+// администраторов, перечисленных в BOT_ADMINS.
+func handleAddTaskCommand(msg *tgbotapi.Message) {
+	userID := msg.From.ID
+	if !isAdmin(userID) {
+		sendMessage(msg.Chat.ID, "Команда доступна только администраторам 🔒")
+		return
+	}
+
+	addTaskDrafts.Store(userID, &taskDraft{Step: wizardStepText})
+	if err := saveUserState(&UserState{UserID: msg.Chat.ID, PosTag: PosTagAddingCustomTask}); err != nil {
+		logger.Error("Ошибка сохранения состояния", "err", err)
+	}
+	sendMessage(msg.Chat.ID, "Введите текст новой задачи:")
+}
+
+// handleAddingCustomTask проводит администратора через шаги мастера
+// /addtask: текст задачи, варианты ответа, правильный ответ.
+func handleAddingCustomTask(msg *tgbotapi.Message, state *UserState) {
+	userID := msg.From.ID
+	if !isAdmin(userID) {
+		resetUserState(state.UserID)
+		return
+	}
+
+	draftVal, ok := addTaskDrafts.Load(userID)
+	if !ok {
+		draftVal = &taskDraft{Step: wizardStepText}
+		addTaskDrafts.Store(userID, draftVal)
+	}
+	draft := draftVal.(*taskDraft)
+
+	switch draft.Step {
+	case wizardStepText:
+		draft.Text = strings.TrimSpace(msg.Text)
+		draft.Step = wizardStepOptions
+		sendMessage(msg.Chat.ID, "Перечислите варианты ответа через запятую:")
+	case wizardStepOptions:
+		options := strings.Split(msg.Text, ",")
+		for i := range options {
+			options[i] = strings.TrimSpace(options[i])
+		}
+		draft.Options = options
+		draft.Step = wizardStepAnswer
+		sendMessage(msg.Chat.ID, "Какой вариант правильный?")
+	case wizardStepAnswer:
+		answer := strings.TrimSpace(msg.Text)
+		task := Task{
+			ID:      nextTaskID(taskStore.All()),
+			Text:    draft.Text,
+			Answer:  answer,
+			Options: draft.Options,
+		}
+		if err := taskStore.Add(task); err != nil {
+			logger.Error("Ошибка добавления задачи", "err", err)
+			sendMessage(msg.Chat.ID, "Не удалось сохранить задачу 😕")
+		} else {
+			sendMessage(msg.Chat.ID, "Задача добавлена ✅")
+		}
+		addTaskDrafts.Delete(userID)
+		resetUserState(state.UserID)
+	}
+}
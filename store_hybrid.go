@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HybridTaskStore читает задачи из файла на диске (тот же формат, что и
+// встроенный tasks.yaml) и перечитывает его по сигналу SIGHUP, не
+// перезапуская бота.
+type HybridTaskStore struct {
+	*MemoryTaskStore
+	path string
+}
+
+func NewHybridTaskStore(path string) *HybridTaskStore {
+	return &HybridTaskStore{MemoryTaskStore: NewMemoryTaskStore(), path: path}
+}
+
+func (s *HybridTaskStore) Load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла задач %s: %v", s.path, err)
+	}
+	return s.loadFromYAML(data)
+}
+
+// Add перезаписывает файл задач (s.path) текущим набором задач вместе с
+// новой и перечитывает его,
+// чтобы хранилище осталось согласовано с диском — иначе задача пережила бы
+// только до следующей перезагрузки по SIGHUP или рестарту процесса.
+func (s *HybridTaskStore) Add(task Task) error {
+	tasks := append(s.All(), task)
+
+	raw := make([]yamlTask, len(tasks))
+	for i, t := range tasks {
+		raw[i] = yamlTask{ID: t.ID, Text: t.Text, Answer: t.Answer, Options: t.Options}
+	}
+
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации задач: %v", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("ошибка записи файла задач %s: %v", s.path, err)
+	}
+
+	return s.Load()
+}
+
+// WatchReload запускает фоновую горутину, перечитывающую файл задач при
+// получении SIGHUP.
+func (s *HybridTaskStore) WatchReload() {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	go func() {
+		for range sighup {
+			if err := s.Load(); err != nil {
+				logger.Error("Ошибка перезагрузки задач", "err", err)
+				continue
+			}
+			logger.Info("Задачи перезагружены", "path", s.path, "version", s.Version())
+		}
+	}()
+}
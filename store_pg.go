@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PgTaskStore хранит задачи в таблицах tasks/task_options, что позволяет
+// редактировать их через /addtask без пересборки бота.
+type PgTaskStore struct {
+	mu      sync.RWMutex
+	tasks   []Task
+	byID    map[int]*Task
+	version int
+}
+
+func NewPgTaskStore() *PgTaskStore {
+	return &PgTaskStore{}
+}
+
+// ensureSchema создаёт таблицы tasks/task_options, если их ещё нет.
+func (s *PgTaskStore) ensureSchema() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS tasks (
+			id INTEGER PRIMARY KEY,
+			text TEXT NOT NULL,
+			answer TEXT NOT NULL
+		);
+	`); err != nil {
+		return fmt.Errorf("ошибка создания таблицы tasks: %v", err)
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS task_options (
+			task_id INTEGER REFERENCES tasks(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			option TEXT NOT NULL,
+			PRIMARY KEY (task_id, position)
+		);
+	`); err != nil {
+		return fmt.Errorf("ошибка создания таблицы task_options: %v", err)
+	}
+
+	return nil
+}
+
+func (s *PgTaskStore) Load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, "SELECT id, text, answer FROM tasks ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки задач: %v", err)
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		var t Task
+		if err := rows.Scan(&t.ID, &t.Text, &t.Answer); err != nil {
+			return fmt.Errorf("ошибка чтения задачи: %v", err)
+		}
+		tasks = append(tasks, t)
+	}
+
+	byID := make(map[int]*Task, len(tasks))
+	for i := range tasks {
+		options, err := s.loadOptions(ctx, tasks[i].ID)
+		if err != nil {
+			return err
+		}
+		tasks[i].Options = options
+		byID[tasks[i].ID] = &tasks[i]
+	}
+
+	s.mu.Lock()
+	s.tasks = tasks
+	s.byID = byID
+	s.version++
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *PgTaskStore) loadOptions(ctx context.Context, taskID int) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT option FROM task_options WHERE task_id = $1 ORDER BY position", taskID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки вариантов ответа: %v", err)
+	}
+	defer rows.Close()
+
+	var options []string
+	for rows.Next() {
+		var option string
+		if err := rows.Scan(&option); err != nil {
+			return nil, fmt.Errorf("ошибка чтения варианта ответа: %v", err)
+		}
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+func (s *PgTaskStore) All() []Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tasks
+}
+
+func (s *PgTaskStore) ByID(id int) (*Task, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.byID[id]
+	return t, ok
+}
+
+// Add сохраняет новую задачу в БД и перечитывает набор задач, чтобы индекс
+// по ID оставался согласован с хранилищем.
+func (s *PgTaskStore) Add(task Task) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, err := db.ExecContext(ctx,
+		"INSERT INTO tasks (id, text, answer) VALUES ($1, $2, $3)",
+		task.ID, task.Text, task.Answer,
+	); err != nil {
+		return fmt.Errorf("ошибка сохранения задачи: %v", err)
+	}
+
+	for i, option := range task.Options {
+		if _, err := db.ExecContext(ctx,
+			"INSERT INTO task_options (task_id, position, option) VALUES ($1, $2, $3)",
+			task.ID, i, option,
+		); err != nil {
+			return fmt.Errorf("ошибка сохранения варианта ответа: %v", err)
+		}
+	}
+
+	return s.Load()
+}
+
+func (s *PgTaskStore) Version() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
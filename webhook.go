@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// startWebhook регистрирует вебхук в Telegram и поднимает HTTP(S)-сервер,
+// который проверяет заголовок X-Telegram-Bot-Api-Secret-Token и
+// публикует полученные обновления в тот же тип канала, что используется
+// processUpdates в режиме long-polling. cfg.WebhookSecret обязателен:
+// без него заголовок было бы не с чем сравнивать, и проверка в
+// webhookHandler молча пропускала бы любой запрос.
+//
+// Возвращаемая tgbotapi.UpdatesChannel — это канал только на чтение, его
+// нельзя закрыть на вызывающей стороне, поэтому вместе с ней отдаём
+// closeUpdates — функцию, закрывающую исходный двунаправленный канал.
+func startWebhook(cfg *Config) (tgbotapi.UpdatesChannel, *http.Server, func()) {
+	if cfg.WebhookSecret == "" {
+		panic("WEBHOOK_SECRET не задан: вебхук без секрета недопустим")
+	}
+	if cfg.TLSCertPath != "" {
+		logger.Warn("TLS_CERT_PATH задан, но регистрация самоподписанного сертификата вместе с secret_token не поддерживается — используйте сертификат доверенного CA перед вебхуком")
+	}
+
+	// tgbotapi.WebhookConfig (v5.5.1) не содержит поля для secret_token,
+	// поэтому регистрируем вебхук напрямую через MakeRequest, чтобы
+	// Telegram действительно присылал заголовок
+	// X-Telegram-Bot-Api-Secret-Token, который сверяет webhookHandler.
+	params := tgbotapi.Params{
+		"url":          cfg.WebhookURL + cfg.WebhookPath,
+		"secret_token": cfg.WebhookSecret,
+	}
+	if _, err := bot.MakeRequest("setWebhook", params); err != nil {
+		logger.Error("Ошибка регистрации вебхука в Telegram", "err", err)
+		panic(err)
+	}
+
+	rawUpdates := make(chan tgbotapi.Update, bot.Buffer)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc(cfg.WebhookPath, webhookHandler(cfg.WebhookSecret, rawUpdates))
+
+	srv := &http.Server{Addr: cfg.WebhookAddr, Handler: mux}
+	go func() {
+		var err error
+		if cfg.TLSCertPath != "" && cfg.TLSKeyPath != "" {
+			err = srv.ListenAndServeTLS(cfg.TLSCertPath, cfg.TLSKeyPath)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("Ошибка HTTP-сервера вебхука", "err", err)
+		}
+	}()
+
+	return rawUpdates, srv, func() { close(rawUpdates) }
+}
+
+// webhookHandler проверяет секрет вебхука и пересылает декодированное
+// обновление в канал, читаемый processUpdates.
+func webhookHandler(secret string, updates chan tgbotapi.Update) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare(
+			[]byte(r.Header.Get("X-Telegram-Bot-Api-Secret-Token")),
+			[]byte(secret),
+		) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		update, err := bot.HandleUpdate(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		updates <- *update
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
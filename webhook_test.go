@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TestWebhookHandlerRejectsMissingSecret проверяет, что без заголовка
+// X-Telegram-Bot-Api-Secret-Token запрос отклоняется — это единственная
+// защита вебхука помимо регистрации secret_token в Telegram через
+// MakeRequest в startWebhook.
+func TestWebhookHandlerRejectsMissingSecret(t *testing.T) {
+	handler := webhookHandler("s3cr3t", make(chan tgbotapi.Update, 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("ожидался статус %d при отсутствии секрета, получен %d", http.StatusUnauthorized, rec.Code)
+	}
+}
+
+// TestWebhookHandlerRejectsWrongSecret проверяет отклонение запроса с
+// неверным значением секрета в заголовке.
+func TestWebhookHandlerRejectsWrongSecret(t *testing.T) {
+	handler := webhookHandler("s3cr3t", make(chan tgbotapi.Update, 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", "wrong")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("ожидался статус %d при неверном секрете, получен %d", http.StatusUnauthorized, rec.Code)
+	}
+}